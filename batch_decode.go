@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+
+	"github.com/makiuchi-d/gozxing"
+	multiqrcode "github.com/makiuchi-d/gozxing/multi/qrcode"
+)
+
+// decodedCode is one QR code found in an image, returned as part of the
+// /api/decode response array.
+type decodedCode struct {
+	Content     string      `json:"content"`
+	Format      string      `json:"format"`
+	Parsed      interface{} `json:"parsed,omitempty"`
+	BoundingBox boundingBox `json:"bounding_box"`
+}
+
+// boundingBox is the axis-aligned box around a detected QR code's finder
+// pattern, in source-image pixel coordinates.
+type boundingBox struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// decodeAllQRCodesFromBytes finds every QR code in an image rather than
+// stopping at the first hit, using the default Decoder options. It's a
+// thin wrapper over decodeAllQRCodesFromBytesWithOptions for callers
+// that don't need to tune the backend, formats, or pipeline.
+func decodeAllQRCodesFromBytes(imageBytes []byte) ([]decodedCode, error) {
+	found, _, err := decodeAllQRCodesFromBytesWithOptions(imageBytes, DefaultDecodeOptions())
+	return found, err
+}
+
+// maxMaskAndRetryPasses caps the mask-and-retry fallback loop in
+// decodeAllQRCodesFromBytesWithOptions, so an image that keeps yielding
+// "new" single-code matches (e.g. from decode noise) can't loop forever.
+const maxMaskAndRetryPasses = 16
+
+// decodeAllQRCodesFromBytesWithOptions finds every QR code in an image
+// rather than stopping at the first hit. It prefers gozxing's
+// MultipleBarcodeReader, which locates several codes from a single
+// finder-pattern scan, and falls back to repeatedly masking out
+// already-found codes and re-running the regular single-code pipeline,
+// so codes the multi-reader misses (e.g. ones needing a preprocessing
+// variant, or an image with more codes than one fallback pass can
+// recover) still get picked up. It returns the per-attempt timing of
+// every fallback pass that returned a code alongside the matches found;
+// a pass in which no backend found a code contributes no timing.
+func decodeAllQRCodesFromBytesWithOptions(imageBytes []byte, opts DecodeOptions) ([]decodedCode, []DecodeAttempt, error) {
+	img, err := decodeAndOrientImage(imageBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	multiReader := multiqrcode.NewQRCodeMultiReader()
+
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_POSSIBLE_FORMATS: opts.Formats,
+		gozxing.DecodeHintType_TRY_HARDER:       true,
+	}
+
+	seen := map[string]bool{}
+	var found []decodedCode
+
+	// Run the multi-reader over the same preprocessing variants the
+	// single-code path uses, since a multi-reader pass over a clean
+	// image can still miss codes that only appear after thresholding.
+	for _, variant := range buildPreprocessedImagesWithConfig(img, opts.Pipeline) {
+		bmp, err := gozxing.NewBinaryBitmapFromImage(variant)
+		if err != nil {
+			continue
+		}
+		results, err := multiReader.DecodeMultiple(bmp, hints)
+		if err != nil {
+			continue
+		}
+		for _, result := range results {
+			key := result.GetText()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			found = append(found, newDecodedCode(result))
+		}
+	}
+
+	// Fallback: mask out the already-found codes and re-run the
+	// single-code pipeline, in case the multi-reader's row-sampling
+	// approach missed a code entirely. Repeat until a pass turns up
+	// nothing new, since an image can hold more codes than one fallback
+	// pass can recover (each pass only ever adds the single code the
+	// single-code pipeline locks onto).
+	var attempts []DecodeAttempt
+	for i := 0; i < maxMaskAndRetryPasses; i++ {
+		masked := maskDecodedRegions(img, found)
+		result, err := decodeWithBackends(masked, opts)
+		if err != nil {
+			break
+		}
+		attempts = append(attempts, result.Attempts...)
+		if seen[result.Text] {
+			break
+		}
+		seen[result.Text] = true
+		format, parsed := classifyContent(result.Text)
+		found = append(found, decodedCode{Content: result.Text, Format: format, Parsed: parsed})
+	}
+
+	if len(found) == 0 {
+		return nil, attempts, fmt.Errorf("no QR code found in image")
+	}
+
+	log.Printf("decodeAllQRCodesFromBytesWithOptions: found %d QR code(s)", len(found))
+	return found, attempts, nil
+}
+
+// newDecodedCode builds a decodedCode from a gozxing result, classifying
+// its content and computing its bounding box from the result points.
+func newDecodedCode(result *gozxing.Result) decodedCode {
+	content := result.GetText()
+	format, parsed := classifyContent(content)
+	return decodedCode{
+		Content:     content,
+		Format:      format,
+		Parsed:      parsed,
+		BoundingBox: boundingBoxFromPoints(result.GetResultPoints()),
+	}
+}
+
+// boundingBoxFromPoints computes the smallest axis-aligned box enclosing
+// a QR code's finder-pattern result points.
+func boundingBoxFromPoints(points []gozxing.ResultPoint) boundingBox {
+	if len(points) == 0 {
+		return boundingBox{}
+	}
+	minX, minY := points[0].GetX(), points[0].GetY()
+	maxX, maxY := minX, minY
+	for _, p := range points[1:] {
+		if p.GetX() < minX {
+			minX = p.GetX()
+		}
+		if p.GetX() > maxX {
+			maxX = p.GetX()
+		}
+		if p.GetY() < minY {
+			minY = p.GetY()
+		}
+		if p.GetY() > maxY {
+			maxY = p.GetY()
+		}
+	}
+	return boundingBox{
+		X:      int(minX),
+		Y:      int(minY),
+		Width:  int(maxX - minX),
+		Height: int(maxY - minY),
+	}
+}
+
+// maskDecodedRegions paints over the bounding box of each already-found
+// code with mid-gray, so a second decode pass can't re-detect them and
+// is free to lock onto a code it previously missed.
+func maskDecodedRegions(img image.Image, found []decodedCode) image.Image {
+	bounds := img.Bounds()
+	masked := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			masked.Set(x, y, img.At(x, y))
+		}
+	}
+	fill := color.Gray{Y: 128}
+	for _, code := range found {
+		b := code.BoundingBox
+		for y := b.Y; y < b.Y+b.Height; y++ {
+			for x := b.X; x < b.X+b.Width; x++ {
+				if (image.Point{X: x, Y: y}).In(bounds) {
+					masked.Set(x, y, fill)
+				}
+			}
+		}
+	}
+	return masked
+}
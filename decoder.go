@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/aztec"
+	"github.com/makiuchi-d/gozxing/datamatrix"
+	"github.com/makiuchi-d/gozxing/oned"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// DecodeAttempt records one (preprocessing stage, strategy) combination
+// a Decoder tried, successful or not, so callers can see where decode
+// time went instead of only getting a final pass/fail.
+type DecodeAttempt struct {
+	Stage    int    `json:"stage"`
+	Strategy int    `json:"strategy"`
+	Micros   int64  `json:"micros"`
+	Matched  bool   `json:"matched"`
+}
+
+// DecodeOptions configures a single decode attempt across Decoder
+// backends: which barcode formats to look for, and which preprocessing
+// stages/scales to run the image through.
+type DecodeOptions struct {
+	Formats  []gozxing.BarcodeFormat
+	Pipeline PipelineConfig
+}
+
+// DefaultDecodeOptions decodes QR codes only, using the full default
+// preprocessing pipeline — this is the long-standing behavior of the
+// service, preserved as the default for existing callers.
+func DefaultDecodeOptions() DecodeOptions {
+	return DecodeOptions{
+		Formats:  []gozxing.BarcodeFormat{gozxing.BarcodeFormat_QR_CODE},
+		Pipeline: DefaultPipelineConfig(),
+	}
+}
+
+// DecodeResult is what a successful Decoder.Decode call returns.
+type DecodeResult struct {
+	Text     string
+	Format   gozxing.BarcodeFormat
+	Attempts []DecodeAttempt
+}
+
+// Decoder is a pluggable barcode-decoding backend. gozxing is the only
+// backend registered today; goqr (the commented-out tryGoQR block in
+// main.go) or a cgo ZBar backend can be added as additional Decoders
+// for images gozxing can't crack, without decodeWithBackends' callers
+// needing to change.
+type Decoder interface {
+	Name() string
+	Decode(img image.Image, opts DecodeOptions) (*DecodeResult, error)
+}
+
+// decoders are the registered backends, tried in order until one
+// succeeds.
+var decoders = []Decoder{gozxingDecoder{}}
+
+// decodeWithBackends runs img through each registered Decoder in turn,
+// returning the first success and, if every backend fails, an error
+// chaining each backend's failure reason.
+func decodeWithBackends(img image.Image, opts DecodeOptions) (*DecodeResult, error) {
+	var lastErr error
+	for _, d := range decoders {
+		result, err := d.Decode(img, opts)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = errors.Join(lastErr, fmt.Errorf("%s: %w", d.Name(), err))
+	}
+	return nil, lastErr
+}
+
+// gozxingDecoder is the default Decoder backend: it wraps the
+// preprocessing-pipeline x decode-hint-strategy search that's long been
+// this project's only decode path.
+type gozxingDecoder struct{}
+
+func (gozxingDecoder) Name() string { return "gozxing" }
+
+func (gozxingDecoder) Decode(img image.Image, opts DecodeOptions) (*DecodeResult, error) {
+	preprocessedImages := buildPreprocessedImagesWithConfig(img, opts.Pipeline)
+	readers := readersFor(opts.Formats)
+	strategies := decodeStrategiesFor(opts.Formats)
+
+	var attempts []DecodeAttempt
+	var lastErr error
+	for i, processedImg := range preprocessedImages {
+		bmp, err := gozxing.NewBinaryBitmapFromImage(processedImg)
+		if err != nil {
+			continue // Skip if bitmap conversion fails
+		}
+
+		for _, reader := range readers {
+			for j, hints := range strategies {
+				start := time.Now()
+				result, err := reader.Decode(bmp, hints)
+				attempts = append(attempts, DecodeAttempt{
+					Stage:    i,
+					Strategy: j,
+					Micros:   time.Since(start).Microseconds(),
+					Matched:  err == nil,
+				})
+				if err == nil {
+					log.Printf("gozxing: decoded after %d attempts (stage #%d, strategy #%d)", len(attempts), i+1, j+1)
+					return &DecodeResult{Text: result.GetText(), Format: result.GetBarcodeFormat(), Attempts: attempts}, nil
+				}
+				lastErr = err
+			}
+		}
+	}
+
+	log.Printf("gozxing: tried %d stages x %d reader(s) x %d strategies = %d attempts without success",
+		len(preprocessedImages), len(readers), len(strategies), len(attempts))
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("could not detect a barcode in image. Tried multiple preprocessing stages and strategies. Last error: %w", lastErr)
+	}
+	return nil, fmt.Errorf("no barcode found in image")
+}
+
+// readersFor builds the gozxing reader for each requested format.
+// gozxing's QRCodeReader (and every other format-specific reader here)
+// ignores the POSSIBLE_FORMATS hint entirely — it only ever decodes its
+// own symbology — so broadening the set of formats a caller can get back
+// means dispatching to the matching reader directly rather than hoping a
+// single reader honors the hint.
+func readersFor(formats []gozxing.BarcodeFormat) []gozxing.Reader {
+	var readers []gozxing.Reader
+	for _, format := range formats {
+		if reader, ok := readerForFormat(format); ok {
+			readers = append(readers, reader)
+		}
+	}
+	if len(readers) == 0 {
+		readers = append(readers, qrcode.NewQRCodeReader())
+	}
+	return readers
+}
+
+// readerForFormat returns the gozxing reader for a single barcode
+// format. gozxing v0.1.1 has no PDF417 reader, so that format (if
+// requested) is silently dropped by readersFor rather than handled here.
+func readerForFormat(format gozxing.BarcodeFormat) (gozxing.Reader, bool) {
+	switch format {
+	case gozxing.BarcodeFormat_QR_CODE:
+		return qrcode.NewQRCodeReader(), true
+	case gozxing.BarcodeFormat_DATA_MATRIX:
+		return datamatrix.NewDataMatrixReader(), true
+	case gozxing.BarcodeFormat_AZTEC:
+		return aztec.NewAztecReader(), true
+	case gozxing.BarcodeFormat_CODE_128:
+		return oned.NewCode128Reader(), true
+	case gozxing.BarcodeFormat_CODE_39:
+		return oned.NewCode39Reader(), true
+	case gozxing.BarcodeFormat_EAN_13:
+		return oned.NewEAN13Reader(), true
+	case gozxing.BarcodeFormat_EAN_8:
+		return oned.NewEAN8Reader(), true
+	case gozxing.BarcodeFormat_UPC_A:
+		return oned.NewUPCAReader(), true
+	case gozxing.BarcodeFormat_UPC_E:
+		return oned.NewUPCEReader(), true
+	default:
+		return nil, false
+	}
+}
+
+// formatsByName maps the query-parameter spelling of a barcode format to
+// its gozxing constant, for the symbologies readerForFormat can actually
+// dispatch to. gozxing v0.1.1 has no PDF417 reader, so "pdf417" isn't
+// accepted here — it would silently decode nothing if it were.
+var formatsByName = map[string]gozxing.BarcodeFormat{
+	"qr":         gozxing.BarcodeFormat_QR_CODE,
+	"datamatrix": gozxing.BarcodeFormat_DATA_MATRIX,
+	"aztec":      gozxing.BarcodeFormat_AZTEC,
+	"code128":    gozxing.BarcodeFormat_CODE_128,
+	"code39":     gozxing.BarcodeFormat_CODE_39,
+	"ean13":      gozxing.BarcodeFormat_EAN_13,
+	"ean8":       gozxing.BarcodeFormat_EAN_8,
+	"upca":       gozxing.BarcodeFormat_UPC_A,
+	"upce":       gozxing.BarcodeFormat_UPC_E,
+}
+
+// ParseFormats turns a comma-separated "formats" query parameter (e.g.
+// "qr,datamatrix,aztec") into gozxing format constants, defaulting to
+// QR-only when the parameter is absent so existing callers are
+// unaffected.
+func ParseFormats(param string) []gozxing.BarcodeFormat {
+	if param == "" {
+		return []gozxing.BarcodeFormat{gozxing.BarcodeFormat_QR_CODE}
+	}
+	var formats []gozxing.BarcodeFormat
+	for _, name := range strings.Split(param, ",") {
+		if f, ok := formatsByName[strings.ToLower(strings.TrimSpace(name))]; ok {
+			formats = append(formats, f)
+		}
+	}
+	if len(formats) == 0 {
+		return []gozxing.BarcodeFormat{gozxing.BarcodeFormat_QR_CODE}
+	}
+	return formats
+}
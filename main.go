@@ -1,8 +1,6 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"image"
 	"image/color"
@@ -19,7 +17,6 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/makiuchi-d/gozxing"
-	"github.com/makiuchi-d/gozxing/qrcode"
 )
 
 func main() {
@@ -40,6 +37,12 @@ func main() {
 	// QR code decode endpoint
 	r.POST("/api/decode", decodeQRCode)
 
+	// QR code generation endpoint
+	r.POST("/api/encode", encodeQRCode)
+
+	// Streaming webcam/video decode endpoint
+	r.GET("/api/decode/ws", decodeQRCodeWS)
+
 	// Start server
 	port := "8080"
 	addr := fmt.Sprintf(":%s", port)
@@ -76,177 +79,81 @@ func decodeQRCode(c *gin.Context) {
 		return
 	}
 
-	// Decode QR code
-	result, err := decodeQRCodeFromBytes(fileBytes)
+	// Formats/stages/scales can be tuned per request via query params,
+	// e.g. ?formats=qr,datamatrix&stages=gray,adaptive&scales=2,4
+	opts := DecodeOptions{
+		Formats:  ParseFormats(c.Query("formats")),
+		Pipeline: ParsePipelineConfig(c.Query("stages"), c.Query("scales")),
+	}
+
+	// Decode every QR code present in the image
+	results, attempts, err := decodeAllQRCodesFromBytesWithOptions(fileBytes, opts)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to decode QR code: " + err.Error()})
 		return
 	}
 
-	// Try to parse as JSON if possible
-	var jsonData interface{}
-	if json.Unmarshal([]byte(result), &jsonData) == nil {
-		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"content": result,
-			"format":  "json",
-			"parsed":  jsonData,
-		})
-	} else {
-		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"content": result,
-			"format":  "text",
-		})
+	response := gin.H{
+		"success": true,
+		"count":   len(results),
+		"results": results,
+	}
+	if c.Query("timing") == "1" {
+		response["attempts"] = attempts
 	}
+	c.JSON(http.StatusOK, response)
 }
 
-func decodeQRCodeFromBytes(imageBytes []byte) (string, error) {
-	// Decode the image using Go's standard image library
-	img, _, err := image.Decode(bytes.NewReader(imageBytes))
+// decodeQRCodeFromImage runs the full preprocessing/strategy pipeline
+// against an already-decoded image and returns the first barcode found,
+// using the default Decoder backend and pipeline config. Callers that
+// want to tune the backend, formats, or pipeline go through
+// decodeWithBackends directly.
+func decodeQRCodeFromImage(img image.Image) (string, error) {
+	result, err := decodeWithBackends(img, DefaultDecodeOptions())
 	if err != nil {
-		return "", fmt.Errorf("failed to decode image: %w", err)
+		return "", err
 	}
+	return result.Text, nil
+}
 
-	// Try multiple preprocessing strategies
-	gray := convertToGrayscale(img)
-	enhanced := enhanceContrast(gray)
-	threshold128 := thresholdImage(gray, 128)
-	threshold100 := thresholdImage(gray, 100)
-	threshold150 := thresholdImage(gray, 150)
-	adaptive := adaptiveThreshold(gray)
-	sharpened := sharpenImage(gray)
-	
-	// Inverted versions (for white-on-dark QR codes like Nametag)
-	invertedGray := invertImage(gray)
-	invertedEnhanced := invertImage(enhanced)
-	invertedThreshold128 := invertImage(threshold128)
-	invertedAdaptive := invertImage(adaptive)
-	
-	preprocessedImages := []image.Image{
-		img,                                    // Original
-		gray,                                   // Grayscale
-		invertedGray,                          // Inverted grayscale (for white-on-dark)
-		enhanced,                              // Grayscale + contrast
-		invertedEnhanced,                      // Inverted enhanced
-		threshold100,                          // Binary threshold (low)
-		threshold128,                          // Binary threshold (mid)
-		invertedThreshold128,                 // Inverted threshold (for white-on-dark)
-		threshold150,                          // Binary threshold (high)
-		adaptive,                              // Adaptive threshold
-		invertedAdaptive,                      // Inverted adaptive
-		sharpened,                             // Sharpened
-		thresholdImage(sharpened, 128),        // Sharpened + threshold
-		invertImage(thresholdImage(sharpened, 128)), // Inverted sharpened+threshold
-	}
-	
-	// Add scaled versions (try even larger scales for very small QR codes)
-	// Prioritize inverted versions since Nametag uses white-on-dark QR codes
-	scales := []float64{2.0, 3.0, 4.0, 5.0, 6.0, 8.0, 10.0}
-	for _, scale := range scales {
-		preprocessedImages = append(preprocessedImages,
-			scaleImage(invertedGray, scale),           // Scaled inverted (priority for white-on-dark)
-			scaleImage(invertedEnhanced, scale),      // Scaled inverted enhanced
-			scaleImage(invertedThreshold128, scale),   // Scaled inverted threshold
-			scaleImage(invertedAdaptive, scale),       // Scaled inverted adaptive
-			scaleImage(img, scale),                    // Scaled original
-			scaleImage(gray, scale),                  // Scaled grayscale
-			scaleImage(enhanced, scale),              // Scaled enhanced
-			scaleImage(threshold128, scale),          // Scaled threshold
-			scaleImage(adaptive, scale),              // Scaled adaptive
-			scaleImage(sharpened, scale),             // Scaled sharpened
-			scaleImage(thresholdImage(sharpened, 128), scale), // Scaled sharpened+threshold
-			scaleImage(invertImage(thresholdImage(sharpened, 128)), scale), // Scaled inverted sharpened+threshold
-		)
-	}
-
-	// Create QR code reader
-	reader := qrcode.NewQRCodeReader()
-
-	// Try multiple decoding strategies
-	strategies := []map[gozxing.DecodeHintType]interface{}{
+// decodeStrategiesFor returns the gozxing decode-hint combinations tried
+// against every preprocessed image, scoped to the requested formats.
+func decodeStrategiesFor(formats []gozxing.BarcodeFormat) []map[gozxing.DecodeHintType]interface{} {
+	return []map[gozxing.DecodeHintType]interface{}{
 		// Strategy 1: Try harder with character set
 		{
-			gozxing.DecodeHintType_POSSIBLE_FORMATS: []gozxing.BarcodeFormat{
-				gozxing.BarcodeFormat_QR_CODE,
-			},
-			gozxing.DecodeHintType_TRY_HARDER: true,
-			gozxing.DecodeHintType_CHARACTER_SET: "UTF-8",
+			gozxing.DecodeHintType_POSSIBLE_FORMATS: formats,
+			gozxing.DecodeHintType_TRY_HARDER:       true,
+			gozxing.DecodeHintType_CHARACTER_SET:    "UTF-8",
 		},
 		// Strategy 2: Try harder without character set
 		{
-			gozxing.DecodeHintType_POSSIBLE_FORMATS: []gozxing.BarcodeFormat{
-				gozxing.BarcodeFormat_QR_CODE,
-			},
-			gozxing.DecodeHintType_TRY_HARDER: true,
+			gozxing.DecodeHintType_POSSIBLE_FORMATS: formats,
+			gozxing.DecodeHintType_TRY_HARDER:       true,
 		},
 		// Strategy 3: Basic attempt
 		{
-			gozxing.DecodeHintType_POSSIBLE_FORMATS: []gozxing.BarcodeFormat{
-				gozxing.BarcodeFormat_QR_CODE,
-			},
+			gozxing.DecodeHintType_POSSIBLE_FORMATS: formats,
 		},
 		// Strategy 4: Try with inverted image
 		{
-			gozxing.DecodeHintType_POSSIBLE_FORMATS: []gozxing.BarcodeFormat{
-				gozxing.BarcodeFormat_QR_CODE,
-			},
-			gozxing.DecodeHintType_TRY_HARDER: true,
-			gozxing.DecodeHintType_ALSO_INVERTED: true,
+			gozxing.DecodeHintType_POSSIBLE_FORMATS: formats,
+			gozxing.DecodeHintType_TRY_HARDER:       true,
+			gozxing.DecodeHintType_ALSO_INVERTED:    true,
 		},
 		// Strategy 5: Pure barcode mode (assumes clean binary image)
 		{
-			gozxing.DecodeHintType_POSSIBLE_FORMATS: []gozxing.BarcodeFormat{
-				gozxing.BarcodeFormat_QR_CODE,
-			},
-			gozxing.DecodeHintType_PURE_BARCODE: true,
+			gozxing.DecodeHintType_POSSIBLE_FORMATS: formats,
+			gozxing.DecodeHintType_PURE_BARCODE:     true,
 		},
 		// Strategy 6: Pure barcode + try harder
 		{
-			gozxing.DecodeHintType_POSSIBLE_FORMATS: []gozxing.BarcodeFormat{
-				gozxing.BarcodeFormat_QR_CODE,
-			},
-			gozxing.DecodeHintType_PURE_BARCODE: true,
-			gozxing.DecodeHintType_TRY_HARDER: true,
+			gozxing.DecodeHintType_POSSIBLE_FORMATS: formats,
+			gozxing.DecodeHintType_PURE_BARCODE:     true,
+			gozxing.DecodeHintType_TRY_HARDER:       true,
 		},
 	}
-
-	var lastErr error
-	var attemptCount int
-	// Try each preprocessed image with each strategy using gozxing
-	for i, processedImg := range preprocessedImages {
-		// Convert image to binary bitmap for gozxing
-		bmp, err := gozxing.NewBinaryBitmapFromImage(processedImg)
-		if err != nil {
-			continue // Skip if bitmap conversion fails
-		}
-
-		// Try with QR code reader
-		for j, hints := range strategies {
-			attemptCount++
-			result, err := reader.Decode(bmp, hints)
-			if err == nil {
-				log.Printf("Successfully decoded QR code after %d attempts (preprocessing #%d, strategy #%d)", 
-					attemptCount, i+1, j+1)
-				return result.GetText(), nil
-			}
-			lastErr = err
-		}
-	}
-	
-	log.Printf("Tried %d preprocessing combinations Ã— %d strategies = %d total attempts", 
-		len(preprocessedImages), len(strategies), attemptCount)
-
-	// Optional: If gozxing failed, you can add goqr as a fallback
-	// Install with: GOPROXY=direct go get github.com/procommerz/goqr
-	// Then uncomment the tryGoQR call below
-
-	// If all strategies failed, return a helpful error message
-	if lastErr != nil {
-		return "", fmt.Errorf("could not detect QR code in image. Tried multiple libraries and preprocessing. Make sure the image contains a clear QR code. Last error: %w", lastErr)
-	}
-
-	return "", fmt.Errorf("failed to decode QR code: no valid QR code found in image")
 }
 
 // Optional: Uncomment this function after installing goqr library
@@ -396,64 +303,6 @@ func thresholdImage(img image.Image, threshold uint8) image.Image {
 	return binary
 }
 
-// adaptiveThreshold applies adaptive thresholding to create a binary image
-func adaptiveThreshold(img image.Image) image.Image {
-	bounds := img.Bounds()
-	binary := image.NewGray(bounds)
-	blockSize := 15 // Size of neighborhood for adaptive threshold
-	
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			// Calculate local mean
-			var sum uint32
-			count := 0
-			
-			startY := y - blockSize/2
-			if startY < bounds.Min.Y {
-				startY = bounds.Min.Y
-			}
-			endY := y + blockSize/2
-			if endY >= bounds.Max.Y {
-				endY = bounds.Max.Y - 1
-			}
-			
-			startX := x - blockSize/2
-			if startX < bounds.Min.X {
-				startX = bounds.Min.X
-			}
-			endX := x + blockSize/2
-			if endX >= bounds.Max.X {
-				endX = bounds.Max.X - 1
-			}
-			
-			for yy := startY; yy <= endY; yy++ {
-				for xx := startX; xx <= endX; xx++ {
-					c := color.GrayModel.Convert(img.At(xx, yy)).(color.Gray)
-					sum += uint32(c.Y)
-					count++
-				}
-			}
-			
-			localMean := uint8(sum / uint32(count))
-			c := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
-			
-			// Use local mean - 10 as threshold
-			threshold := localMean
-			if threshold > 10 {
-				threshold -= 10
-			}
-			
-			if c.Y > threshold {
-				binary.Set(x, y, color.Gray{Y: 255}) // White
-			} else {
-				binary.Set(x, y, color.Gray{Y: 0}) // Black
-			}
-		}
-	}
-	
-	return binary
-}
-
 // invertImage inverts the colors of an image (black becomes white, white becomes black)
 func invertImage(img image.Image) image.Image {
 	bounds := img.Bounds()
@@ -515,43 +364,6 @@ func sharpenImage(img image.Image) image.Image {
 	return sharpened
 }
 
-// scaleImage scales an image by the given factor using nearest neighbor
-func scaleImage(img image.Image, factor float64) image.Image {
-	bounds := img.Bounds()
-	newWidth := int(float64(bounds.Dx()) * factor)
-	newHeight := int(float64(bounds.Dy()) * factor)
-	
-	// Use Gray for grayscale images, RGBA for color
-	if _, ok := img.(*image.Gray); ok {
-		scaled := image.NewGray(image.Rect(0, 0, newWidth, newHeight))
-		for y := 0; y < newHeight; y++ {
-			for x := 0; x < newWidth; x++ {
-				srcX := bounds.Min.X + int(float64(x)/factor)
-				srcY := bounds.Min.Y + int(float64(y)/factor)
-				
-				if srcX < bounds.Max.X && srcY < bounds.Max.Y {
-					scaled.Set(x, y, img.At(srcX, srcY))
-				}
-			}
-		}
-		return scaled
-	}
-	
-	scaled := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
-	for y := 0; y < newHeight; y++ {
-		for x := 0; x < newWidth; x++ {
-			srcX := bounds.Min.X + int(float64(x)/factor)
-			srcY := bounds.Min.Y + int(float64(y)/factor)
-			
-			if srcX < bounds.Max.X && srcY < bounds.Max.Y {
-				scaled.Set(x, y, img.At(srcX, srcY))
-			}
-		}
-	}
-	
-	return scaled
-}
-
 func openBrowser(url string) {
 	// Wait a moment for the server to start
 	time.Sleep(1 * time.Second)
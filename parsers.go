@@ -0,0 +1,569 @@
+package main
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var errInvalidProtobuf = errors.New("invalid otpauth-migration protobuf payload")
+
+// classifyContent inspects a decoded QR payload and returns a response
+// "format" tag plus, where recognizable, a structured "parsed" value so
+// callers can render account/network/contact details directly instead
+// of re-parsing the raw text themselves.
+func classifyContent(content string) (format string, parsed interface{}) {
+	trimmed := strings.TrimSpace(content)
+
+	switch {
+	case strings.HasPrefix(trimmed, "BEGIN:VCARD"):
+		if v, err := parseVCard(trimmed); err == nil {
+			return "vcard", v
+		}
+	case strings.HasPrefix(trimmed, "MECARD:"):
+		if v, err := parseMeCard(trimmed); err == nil {
+			return "mecard", v
+		}
+	case strings.HasPrefix(trimmed, "WIFI:"):
+		if v, err := parseWiFi(trimmed); err == nil {
+			return "wifi", v
+		}
+	case strings.HasPrefix(trimmed, "otpauth-migration://"):
+		if v, err := parseOTPAuthMigration(trimmed); err == nil {
+			return "otpauth-migration", v
+		}
+	case strings.HasPrefix(trimmed, "otpauth://"):
+		if v, err := parseOTPAuth(trimmed); err == nil {
+			return "otpauth", v
+		}
+	case strings.HasPrefix(trimmed, "geo:"):
+		if v, err := parseGeo(trimmed); err == nil {
+			return "geo", v
+		}
+	case strings.HasPrefix(trimmed, "mailto:"):
+		return "mailto", mailtoPayload{Address: strings.TrimPrefix(trimmed, "mailto:")}
+	case strings.HasPrefix(trimmed, "tel:"):
+		return "tel", telPayload{Number: strings.TrimPrefix(trimmed, "tel:")}
+	case strings.HasPrefix(strings.ToUpper(trimmed), "SMSTO:"):
+		if v, err := parseSMSTO(trimmed); err == nil {
+			return "sms", v
+		}
+	case strings.HasPrefix(trimmed, "bitcoin:"):
+		if v, err := parseCryptoURI(trimmed, "bitcoin"); err == nil {
+			return "bitcoin", v
+		}
+	case strings.HasPrefix(trimmed, "ethereum:"):
+		if v, err := parseCryptoURI(trimmed, "ethereum"); err == nil {
+			return "ethereum", v
+		}
+	case isEMVCoPayload(trimmed):
+		if v, err := parseEMVCo(trimmed); err == nil {
+			return "emvco", v
+		}
+	}
+
+	var jsonData interface{}
+	if json.Unmarshal([]byte(trimmed), &jsonData) == nil {
+		return "json", jsonData
+	}
+	return "text", nil
+}
+
+// vCardPayload holds the handful of vCard 3.0/4.0 fields callers
+// typically need; unrecognized lines are preserved in Raw.
+type vCardPayload struct {
+	FormattedName string            `json:"formatted_name"`
+	Org           string            `json:"org,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Phones        []string          `json:"phones,omitempty"`
+	Emails        []string          `json:"emails,omitempty"`
+	Address       string            `json:"address,omitempty"`
+	URL           string            `json:"url,omitempty"`
+	Raw           map[string]string `json:"raw,omitempty"`
+}
+
+func parseVCard(content string) (vCardPayload, error) {
+	var v vCardPayload
+	v.Raw = map[string]string{}
+	for _, line := range splitLines(content) {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// Strip TYPE=...;ENCODING=... parameters, e.g. "TEL;TYPE=CELL".
+		key, _, _ = strings.Cut(key, ";")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "FN":
+			v.FormattedName = value
+		case "ORG":
+			v.Org = value
+		case "TITLE":
+			v.Title = value
+		case "TEL":
+			v.Phones = append(v.Phones, value)
+		case "EMAIL":
+			v.Emails = append(v.Emails, value)
+		case "ADR":
+			v.Address = value
+		case "URL":
+			v.URL = value
+		case "BEGIN", "END", "VERSION":
+			// structural, not data
+		default:
+			v.Raw[key] = value
+		}
+	}
+	return v, nil
+}
+
+// meCardPayload mirrors the handful of fields used by MECARD: QR codes,
+// a simpler precursor to vCard popular on Japanese feature phones.
+type meCardPayload struct {
+	Name    string   `json:"name,omitempty"`
+	Phones  []string `json:"phones,omitempty"`
+	Emails  []string `json:"emails,omitempty"`
+	Address string   `json:"address,omitempty"`
+	URL     string   `json:"url,omitempty"`
+	Note    string   `json:"note,omitempty"`
+}
+
+func parseMeCard(content string) (meCardPayload, error) {
+	body := strings.TrimSuffix(strings.TrimPrefix(content, "MECARD:"), ";")
+	var m meCardPayload
+	for _, field := range splitUnescaped(body, ';') {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+		value = unescapeMeCard(value)
+		switch strings.ToUpper(key) {
+		case "N":
+			m.Name = value
+		case "TEL":
+			m.Phones = append(m.Phones, value)
+		case "EMAIL":
+			m.Emails = append(m.Emails, value)
+		case "ADR":
+			m.Address = value
+		case "URL":
+			m.URL = value
+		case "NOTE":
+			m.Note = value
+		}
+	}
+	return m, nil
+}
+
+// wiFiPayload is the network configuration carried by a WIFI: QR code.
+type wiFiPayload struct {
+	SSID     string `json:"ssid"`
+	Password string `json:"password,omitempty"`
+	Security string `json:"security,omitempty"`
+	Hidden   bool   `json:"hidden"`
+}
+
+func parseWiFi(content string) (wiFiPayload, error) {
+	body := strings.TrimSuffix(strings.TrimPrefix(content, "WIFI:"), ";")
+	var w wiFiPayload
+	for _, field := range splitUnescaped(body, ';') {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+		value = unescapeMeCard(value)
+		switch strings.ToUpper(key) {
+		case "S":
+			w.SSID = value
+		case "P":
+			w.Password = value
+		case "T":
+			w.Security = value
+		case "H":
+			w.Hidden = strings.EqualFold(value, "true")
+		}
+	}
+	return w, nil
+}
+
+// geoPayload is a "geo:lat,lon" or "geo:lat,lon,alt" URI.
+type geoPayload struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float64 `json:"altitude,omitempty"`
+}
+
+func parseGeo(content string) (geoPayload, error) {
+	body := strings.TrimPrefix(content, "geo:")
+	body, _, _ = strings.Cut(body, "?")
+	parts := strings.Split(body, ",")
+	var g geoPayload
+	var err error
+	if len(parts) > 0 {
+		g.Latitude, err = strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return g, err
+		}
+	}
+	if len(parts) > 1 {
+		g.Longitude, err = strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return g, err
+		}
+	}
+	if len(parts) > 2 {
+		g.Altitude, _ = strconv.ParseFloat(parts[2], 64)
+	}
+	return g, nil
+}
+
+type mailtoPayload struct {
+	Address string `json:"address"`
+}
+
+type telPayload struct {
+	Number string `json:"number"`
+}
+
+type smsPayload struct {
+	Number string `json:"number"`
+	Body   string `json:"body,omitempty"`
+}
+
+func parseSMSTO(content string) (smsPayload, error) {
+	body := content[len("SMSTO:"):]
+	number, msg, _ := strings.Cut(body, ":")
+	return smsPayload{Number: number, Body: msg}, nil
+}
+
+// cryptoURIPayload covers BIP-21 "bitcoin:" and EIP-681 "ethereum:" payment URIs.
+type cryptoURIPayload struct {
+	Scheme  string            `json:"scheme"`
+	Address string            `json:"address"`
+	Amount  string            `json:"amount,omitempty"`
+	Params  map[string]string `json:"params,omitempty"`
+}
+
+func parseCryptoURI(content, scheme string) (cryptoURIPayload, error) {
+	body := strings.TrimPrefix(content, scheme+":")
+	address, query, _ := strings.Cut(body, "?")
+	p := cryptoURIPayload{Scheme: scheme, Address: address, Params: map[string]string{}}
+	for _, kv := range strings.Split(query, "&") {
+		if kv == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(kv, "=")
+		if strings.EqualFold(key, "amount") {
+			p.Amount = value
+		} else if key != "" {
+			p.Params[key] = value
+		}
+	}
+	return p, nil
+}
+
+// isEMVCoPayload does a cheap structural check for an EMVCo/UPI merchant
+// QR string: TLV fields starting with the "00" payload-format-indicator
+// tag, ending in the mandatory CRC (tag "63") field.
+func isEMVCoPayload(content string) bool {
+	return strings.HasPrefix(content, "000201") && len(content) >= 14 &&
+		content[len(content)-8:len(content)-4] == "6304"
+}
+
+// emvcoPayload exposes the handful of EMVCo/UPI merchant-QR fields most
+// callers care about; every top-level TLV field is kept in Fields for
+// anything more exotic (tip/convenience fees, additional data templates,
+// etc). Nested templates stay as their raw, undecoded value.
+type emvcoPayload struct {
+	MerchantName      string            `json:"merchant_name,omitempty"`
+	MerchantCity      string            `json:"merchant_city,omitempty"`
+	MerchantCategory  string            `json:"merchant_category_code,omitempty"`
+	CountryCode       string            `json:"country_code,omitempty"`
+	TransactionAmount string            `json:"transaction_amount,omitempty"`
+	CurrencyCode      string            `json:"currency_code,omitempty"`
+	CRC               string            `json:"crc"`
+	Fields            map[string]string `json:"fields"`
+}
+
+func parseEMVCo(content string) (emvcoPayload, error) {
+	fields, err := parseTLV(content)
+	if err != nil {
+		return emvcoPayload{}, err
+	}
+	return emvcoPayload{
+		MerchantName:      fields["59"],
+		MerchantCity:      fields["60"],
+		MerchantCategory:  fields["52"],
+		CountryCode:       fields["58"],
+		TransactionAmount: fields["54"],
+		CurrencyCode:      fields["53"],
+		CRC:               fields["63"],
+		Fields:            fields,
+	}, nil
+}
+
+// parseTLV decodes an EMVCo tag-length-value string: each field is a
+// 2-digit tag, a 2-digit length, then that many characters of value.
+func parseTLV(s string) (map[string]string, error) {
+	fields := map[string]string{}
+	for len(s) >= 4 {
+		tag := s[0:2]
+		length, err := strconv.Atoi(s[2:4])
+		if err != nil {
+			return nil, err
+		}
+		if 4+length > len(s) {
+			break
+		}
+		fields[tag] = s[4 : 4+length]
+		s = s[4+length:]
+	}
+	return fields, nil
+}
+
+// otpAuthPayload is a single TOTP/HOTP account as carried by an
+// "otpauth://" URI (the format used by Google Authenticator and
+// compatible MFA apps).
+type otpAuthPayload struct {
+	Type      string `json:"type"` // totp or hotp
+	Label     string `json:"label"`
+	Issuer    string `json:"issuer,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Digits    int    `json:"digits,omitempty"`
+	Period    int    `json:"period,omitempty"`
+	Counter   int    `json:"counter,omitempty"`
+}
+
+func parseOTPAuth(content string) (otpAuthPayload, error) {
+	u, err := url.Parse(content)
+	if err != nil {
+		return otpAuthPayload{}, err
+	}
+	q := u.Query()
+	p := otpAuthPayload{
+		Type:      u.Host,
+		Label:     strings.TrimPrefix(u.Path, "/"),
+		Issuer:    q.Get("issuer"),
+		Secret:    q.Get("secret"),
+		Algorithm: q.Get("algorithm"),
+	}
+	if d, err := strconv.Atoi(q.Get("digits")); err == nil {
+		p.Digits = d
+	}
+	if per, err := strconv.Atoi(q.Get("period")); err == nil {
+		p.Period = per
+	}
+	if cnt, err := strconv.Atoi(q.Get("counter")); err == nil {
+		p.Counter = cnt
+	}
+	return p, nil
+}
+
+// otpMigrationAccount is one account carried inside an
+// "otpauth-migration://" payload (the QR code Google Authenticator's
+// "Export accounts" feature produces).
+type otpMigrationAccount struct {
+	Name      string `json:"name"`
+	Issuer    string `json:"issuer,omitempty"`
+	Secret    string `json:"secret"` // base32-encoded
+	Algorithm string `json:"algorithm"`
+	Digits    int    `json:"digits"`
+	Type      string `json:"type"` // totp or hotp
+}
+
+type otpAuthMigrationPayload struct {
+	Accounts []otpMigrationAccount `json:"accounts"`
+}
+
+// parseOTPAuthMigration decodes the base64url "data" parameter of an
+// otpauth-migration:// URI. That payload is a small hand-rolled
+// protobuf message (google/authenticator MigrationPayload), so rather
+// than pull in a full protobuf runtime for one message shape we walk
+// the wire format ourselves: each OtpParameters entry is a
+// length-delimited field (tag 1) containing sub-fields for secret (1),
+// name (2), issuer (3), algorithm (4), digits (5) and type (6).
+func parseOTPAuthMigration(content string) (otpAuthMigrationPayload, error) {
+	u, err := url.Parse(content)
+	if err != nil {
+		return otpAuthMigrationPayload{}, err
+	}
+	data := u.Query().Get("data")
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		raw, err = base64.URLEncoding.DecodeString(data)
+		if err != nil {
+			return otpAuthMigrationPayload{}, err
+		}
+	}
+
+	var out otpAuthMigrationPayload
+	fields, err := decodeProtobufFields(raw)
+	if err != nil {
+		return out, err
+	}
+	for _, entry := range fields[1] { // repeated OtpParameters otp_parameters = 1
+		sub, err := decodeProtobufFields(entry)
+		if err != nil {
+			continue
+		}
+		acct := otpMigrationAccount{
+			Secret:    base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(firstBytes(sub[1])),
+			Name:      string(firstBytes(sub[2])),
+			Issuer:    string(firstBytes(sub[3])),
+			Algorithm: otpMigrationAlgorithm(firstVarint(sub[4])),
+			Digits:    otpMigrationDigits(firstVarint(sub[5])),
+			Type:      otpMigrationType(firstVarint(sub[6])),
+		}
+		out.Accounts = append(out.Accounts, acct)
+	}
+	return out, nil
+}
+
+func otpMigrationAlgorithm(v int64) string {
+	switch v {
+	case 1:
+		return "SHA1"
+	case 2:
+		return "SHA256"
+	case 3:
+		return "SHA512"
+	case 4:
+		return "MD5"
+	default:
+		return "SHA1"
+	}
+}
+
+func otpMigrationDigits(v int64) int {
+	switch v {
+	case 2:
+		return 8
+	default:
+		return 6
+	}
+}
+
+func otpMigrationType(v int64) string {
+	if v == 1 {
+		return "hotp"
+	}
+	return "totp"
+}
+
+// protobufFields maps a field number to the raw bytes of each occurrence
+// of that field: length-delimited contents (strings, sub-messages) as
+// they appear, and varint/fixed values as little-endian bytes.
+type protobufFields map[int][][]byte
+
+// decodeProtobufFields walks a protobuf wire-format message and groups
+// each field's raw bytes by field number. Varint and fixed32/fixed64
+// fields are stored as their little-endian byte encoding of the
+// underlying value; callers use firstVarint/firstBytes to interpret.
+func decodeProtobufFields(b []byte) (protobufFields, error) {
+	fields := protobufFields{}
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errInvalidProtobuf
+		}
+		b = b[n:]
+		fieldNum := int(tag >> 3)
+		wireType := tag & 0x7
+
+		switch wireType {
+		case 0: // varint
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return nil, errInvalidProtobuf
+			}
+			buf := make([]byte, 8)
+			binary.LittleEndian.PutUint64(buf, v)
+			fields[fieldNum] = append(fields[fieldNum], buf)
+			b = b[n:]
+		case 2: // length-delimited
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				return nil, errInvalidProtobuf
+			}
+			b = b[n:]
+			fields[fieldNum] = append(fields[fieldNum], b[:length])
+			b = b[length:]
+		case 1: // fixed64
+			if len(b) < 8 {
+				return nil, errInvalidProtobuf
+			}
+			fields[fieldNum] = append(fields[fieldNum], b[:8])
+			b = b[8:]
+		case 5: // fixed32
+			if len(b) < 4 {
+				return nil, errInvalidProtobuf
+			}
+			fields[fieldNum] = append(fields[fieldNum], b[:4])
+			b = b[4:]
+		default:
+			return nil, errInvalidProtobuf
+		}
+	}
+	return fields, nil
+}
+
+func firstBytes(values [][]byte) []byte {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+func firstVarint(values [][]byte) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var buf [8]byte
+	copy(buf[:], values[0])
+	return int64(binary.LittleEndian.Uint64(buf[:]))
+}
+
+// splitLines splits vCard content on CRLF or LF line endings.
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(s, "\n")
+}
+
+// splitUnescaped splits on sep, but treats a backslash-escaped sep as a
+// literal character rather than a delimiter (MECARD/WIFI field syntax).
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	var cur strings.Builder
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			cur.WriteByte(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == sep:
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	if cur.Len() > 0 {
+		parts = append(parts, cur.String())
+	}
+	return parts
+}
+
+func unescapeMeCard(s string) string {
+	replacer := strings.NewReplacer(`\:`, ":", `\;`, ";", `\,`, ",", `\\`, `\`)
+	return replacer.Replace(s)
+}
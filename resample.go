@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"image"
+
+	"github.com/disintegration/imaging"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// decodeAndOrientImage decodes an uploaded image and, for JPEGs carrying
+// an EXIF orientation tag, rotates/flips it upright first. Phone cameras
+// routinely store photos rotated and rely on the viewer to apply this
+// tag; skipping it means gozxing's finder-pattern search silently fails
+// on a QR code that's sideways or upside down in pixel space.
+func decodeAndOrientImage(imageBytes []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(imageBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	x, err := exif.Decode(bytes.NewReader(imageBytes))
+	if err != nil {
+		// No EXIF data (or not a JPEG) — use the image as decoded.
+		return img, nil
+	}
+	tag, err := x.Get(exif.Orientation)
+	if err != nil {
+		return img, nil
+	}
+	orientation, err := tag.Int(0)
+	if err != nil {
+		return img, nil
+	}
+	return applyExifOrientation(img, orientation), nil
+}
+
+// applyExifOrientation rotates/flips img according to the EXIF
+// orientation values 1-8 (TIFF/EXIF spec, section on Orientation tag).
+func applyExifOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return imaging.FlipH(img)
+	case 3:
+		return imaging.Rotate180(img)
+	case 4:
+		return imaging.FlipV(img)
+	case 5:
+		return imaging.Transpose(img)
+	case 6:
+		return imaging.Rotate270(img)
+	case 7:
+		return imaging.Transverse(img)
+	case 8:
+		return imaging.Rotate90(img)
+	default:
+		return img
+	}
+}
+
+// scaleImage resizes an image by the given factor using Lanczos
+// resampling. Lanczos (and CatmullRom for the common downscale case)
+// produce much cleaner edges than nearest-neighbor at non-integer
+// scales, which matters a lot for gozxing's finder-pattern detection on
+// blocky upscales of small QR codes.
+func scaleImage(img image.Image, factor float64) image.Image {
+	bounds := img.Bounds()
+	newWidth := int(float64(bounds.Dx()) * factor)
+	newHeight := int(float64(bounds.Dy()) * factor)
+
+	filter := imaging.Lanczos
+	if factor < 1.0 {
+		filter = imaging.CatmullRom
+	}
+	return imaging.Resize(img, newWidth, newHeight, filter)
+}
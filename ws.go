@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// wsUpgrader upgrades the HTTP connection to a WebSocket. CORS for the
+// REST endpoints is handled by gin-contrib/cors; this origin check is
+// permissive for the same reason that middleware is (this server has no
+// notion of an allowed origin list today).
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsFrameMessage is one inbound message: a JPEG frame plus, optionally,
+// a client-set stop_on_first flag, which stays in effect for the rest of
+// the connection once any frame sets it. Most callers send raw binary
+// frames instead and should set stop_on_first via the query parameter
+// at connect time, since a binary frame has nowhere to carry this flag.
+type wsFrameMessage struct {
+	Frame       []byte `json:"frame"` // JPEG bytes, sent as a binary WebSocket message in practice
+	StopOnFirst bool   `json:"stop_on_first"`
+}
+
+// wsResultMessage is what we push back for each frame we attempt.
+type wsResultMessage struct {
+	Success bool        `json:"success"`
+	Content string      `json:"content,omitempty"`
+	Format  string      `json:"format,omitempty"`
+	Parsed  interface{} `json:"parsed,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// wsWorkingWidth is the width frames are downscaled to before decoding.
+// getUserMedia frames are often 720p+; gozxing doesn't need that much
+// resolution and decoding every frame at full size would fall behind a
+// live camera feed.
+const wsWorkingWidth = 640
+
+// wsMaxFrameBytes caps a single frame message, matching the 10MB upload
+// cap /api/decode enforces. Without it an unauthenticated, long-lived
+// socket (CheckOrigin always allows) could be fed unbounded frame sizes.
+const wsMaxFrameBytes = 10 * 1024 * 1024
+
+// decodeQRCodeWS streams decode results for a live sequence of JPEG
+// frames sent over a WebSocket (e.g. from the browser's getUserMedia).
+// Frames arrive as binary messages; results are pushed back as JSON as
+// soon as each frame is processed. If stop_on_first is set — via the
+// "stop_on_first" query parameter at connect time, or on any frame sent
+// as a JSON text message — the connection closes after the first
+// successful decode, useful for kiosk-style single-scan flows. Otherwise
+// it keeps streaming detections until the client disconnects.
+func decodeQRCodeWS(c *gin.Context) {
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("decodeQRCodeWS: upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(wsMaxFrameBytes)
+
+	// One reader per connection, reused across frames rather than
+	// constructed per attempt, since the caller is now a hot loop
+	// instead of a single HTTP request.
+	reader := qrcode.NewQRCodeReader()
+	lightHints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_POSSIBLE_FORMATS: []gozxing.BarcodeFormat{
+			gozxing.BarcodeFormat_QR_CODE,
+		},
+	}
+	stopOnFirst := c.Query("stop_on_first") == "true"
+
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			return // client disconnected
+		}
+
+		var frame []byte
+		switch msgType {
+		case websocket.BinaryMessage:
+			frame = data
+		case websocket.TextMessage:
+			var msg wsFrameMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				continue
+			}
+			frame = msg.Frame
+			stopOnFirst = stopOnFirst || msg.StopOnFirst
+		default:
+			continue
+		}
+
+		content, format, parsed, err := decodeWSFrame(reader, lightHints, frame)
+		if err != nil {
+			conn.WriteJSON(wsResultMessage{Success: false, Error: err.Error()})
+			continue
+		}
+
+		conn.WriteJSON(wsResultMessage{Success: true, Content: content, Format: format, Parsed: parsed})
+		if stopOnFirst {
+			return
+		}
+	}
+}
+
+// decodeWSFrame decodes a single JPEG frame. It first tries a
+// light-weight pass (downscaled grayscale, no preprocessing variants) so
+// a typical in-focus frame decodes in well under a frame interval; only
+// on failure does it fall back to the full preprocessing pipeline,
+// which is too slow to run on every frame of a live stream.
+func decodeWSFrame(reader gozxing.Reader, lightHints map[gozxing.DecodeHintType]interface{}, frame []byte) (content, format string, parsed interface{}, err error) {
+	img, decErr := decodeAndOrientImage(frame)
+	if decErr != nil {
+		return "", "", nil, decErr
+	}
+
+	working := img
+	if img.Bounds().Dx() > wsWorkingWidth {
+		scale := float64(wsWorkingWidth) / float64(img.Bounds().Dx())
+		working = scaleImage(img, scale)
+	}
+	gray := convertToGrayscale(working)
+
+	if bmp, bmpErr := gozxing.NewBinaryBitmapFromImage(gray); bmpErr == nil {
+		if result, decErr := reader.Decode(bmp, lightHints); decErr == nil {
+			format, parsed = classifyContent(result.GetText())
+			return result.GetText(), format, parsed, nil
+		}
+	}
+
+	// Light pass failed — fall back to the full pipeline for this frame.
+	text, fullErr := decodeQRCodeFromImage(working)
+	if fullErr != nil {
+		return "", "", nil, fullErr
+	}
+	format, parsed = classifyContent(text)
+	return text, format, parsed, nil
+}